@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// analyzeRequestXML mirrors analyzeRequest for XML bodies. encoding/xml
+// needs its own struct tags, so it can't share analyzeRequest's json tags.
+type analyzeRequestXML struct {
+	XMLName  xml.Name `xml:"analyzeRequest"`
+	Messages []string `xml:"messages>message"`
+	Stream   bool     `xml:"stream"`
+}
+
+// bindAnalyzeRequest decodes an analyzeRequest from r using the strategy
+// implied by its method and Content-Type: JSON and XML bodies, HTML form
+// and multipart submissions (including an uploaded message-batch file,
+// one message per line), and repeated query parameters on GET requests.
+// This lets /api/analyze be driven from HTML forms, curl one-liners, and
+// legacy XML pipelines without a JSON preprocessing step.
+func bindAnalyzeRequest(r *http.Request) (analyzeRequest, error) {
+	if r.Method == http.MethodGet {
+		return bindAnalyzeRequestQuery(r), nil
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(contentType)
+	}
+
+	switch mediaType {
+	case "", "application/json":
+		var req analyzeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return analyzeRequest{}, fmt.Errorf("error decoding JSON body: %w", err)
+		}
+		return req, nil
+
+	case "application/xml", "text/xml":
+		var req analyzeRequestXML
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			return analyzeRequest{}, fmt.Errorf("error decoding XML body: %w", err)
+		}
+		return analyzeRequest{Messages: req.Messages, Stream: req.Stream}, nil
+
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return analyzeRequest{}, fmt.Errorf("error parsing form body: %w", err)
+		}
+		return analyzeRequest{
+			Messages: r.PostForm["message"],
+			Stream:   isTruthy(r.PostForm.Get("stream")),
+		}, nil
+
+	case "multipart/form-data":
+		return bindAnalyzeRequestMultipart(r)
+
+	default:
+		return analyzeRequest{}, fmt.Errorf("unsupported content type %q", contentType)
+	}
+}
+
+// maxUploadSize bounds the in-memory portion of a multipart message-batch
+// upload; larger file parts spill to temp files as usual for
+// multipart.Form.
+const maxUploadSize = 32 << 20 // 32MB
+
+func bindAnalyzeRequestMultipart(r *http.Request) (analyzeRequest, error) {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		return analyzeRequest{}, fmt.Errorf("error parsing multipart body: %w", err)
+	}
+
+	messages := append([]string{}, r.MultipartForm.Value["message"]...)
+
+	for _, fileHeader := range r.MultipartForm.File["messages"] {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return analyzeRequest{}, fmt.Errorf("error opening uploaded file: %w", err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				messages = append(messages, line)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return analyzeRequest{}, fmt.Errorf("error reading uploaded file: %w", err)
+		}
+	}
+
+	return analyzeRequest{
+		Messages: messages,
+		Stream:   isTruthy(r.FormValue("stream")),
+	}, nil
+}
+
+func bindAnalyzeRequestQuery(r *http.Request) analyzeRequest {
+	query := r.URL.Query()
+	return analyzeRequest{
+		Messages: query["message"],
+		Stream:   isTruthy(query.Get("stream")),
+	}
+}
+
+func isTruthy(v string) bool {
+	return v == "1" || strings.EqualFold(v, "true")
+}
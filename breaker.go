@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// breakerState is the state of a circuitBreaker.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// errBreakerOpen is returned by circuitBreaker.Allow when the breaker is
+// tripped and not yet due for a half-open probe.
+var errBreakerOpen = errors.New("circuit breaker is open")
+
+// circuitBreaker trips to the open state after consecutiveFailures
+// consecutive failures, rejecting calls for resetTimeout before allowing a
+// single half-open probe through. A successful probe closes the breaker; a
+// failed probe re-opens it.
+type circuitBreaker struct {
+	consecutiveFailures int
+	resetTimeout        time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	trips    int64
+}
+
+func newCircuitBreaker(consecutiveFailures int, resetTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		consecutiveFailures: consecutiveFailures,
+		resetTimeout:        resetTimeout,
+	}
+}
+
+// Allow reports whether a call may proceed, transitioning an open breaker
+// to half-open once resetTimeout has elapsed. Only the caller that
+// performs that transition is let through as the trial probe; every other
+// caller is rejected with errBreakerOpen until the probe resolves via
+// RecordSuccess or RecordFailure.
+func (b *circuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.resetTimeout {
+			return errBreakerOpen
+		}
+		b.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		return errBreakerOpen
+	default:
+		return nil
+	}
+}
+
+// RecordSuccess closes the breaker and resets the failure count.
+func (b *circuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.state = breakerClosed
+}
+
+// RecordFailure increments the failure count, tripping the breaker open
+// once consecutiveFailures is reached (or immediately if a half-open probe
+// failed).
+func (b *circuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.trip()
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.consecutiveFailures {
+		b.trip()
+	}
+}
+
+func (b *circuitBreaker) trip() {
+	b.state = breakerOpen
+	b.openedAt = time.Now()
+	b.trips++
+}
+
+// breakerStats is a point-in-time snapshot of a circuitBreaker, suitable
+// for embedding in a health check response.
+type breakerStats struct {
+	State string `json:"state"`
+	Trips int64  `json:"trips"`
+}
+
+func (b *circuitBreaker) Stats() breakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return breakerStats{State: b.state.String(), Trips: b.trips}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTripsAfterConsecutiveFailures(t *testing.T) {
+	b := newCircuitBreaker(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow() = %v before the breaker should trip", err)
+		}
+		b.RecordFailure()
+	}
+	if stats := b.Stats(); stats.State != "closed" {
+		t.Fatalf("state = %q after 2 of 3 failures, want closed", stats.State)
+	}
+
+	b.RecordFailure()
+	stats := b.Stats()
+	if stats.State != "open" {
+		t.Fatalf("state = %q after 3 consecutive failures, want open", stats.State)
+	}
+	if stats.Trips != 1 {
+		t.Fatalf("trips = %d, want 1", stats.Trips)
+	}
+
+	if err := b.Allow(); !errors.Is(err, errBreakerOpen) {
+		t.Fatalf("Allow() = %v, want errBreakerOpen", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbeSucceeds(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+
+	b.RecordFailure() // trips the breaker
+	if err := b.Allow(); !errors.Is(err, errBreakerOpen) {
+		t.Fatalf("Allow() = %v, want errBreakerOpen immediately after tripping", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() = %v after resetTimeout elapsed, want nil (half-open probe)", err)
+	}
+	if stats := b.Stats(); stats.State != "half-open" {
+		t.Fatalf("state = %q after resetTimeout elapsed, want half-open", stats.State)
+	}
+
+	b.RecordSuccess()
+	if stats := b.Stats(); stats.State != "closed" {
+		t.Fatalf("state = %q after a successful probe, want closed", stats.State)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbeFails(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+
+	b.RecordFailure()
+	time.Sleep(20 * time.Millisecond)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() = %v, want nil (half-open probe)", err)
+	}
+
+	b.RecordFailure() // failed probe re-opens immediately
+	stats := b.Stats()
+	if stats.State != "open" {
+		t.Fatalf("state = %q after a failed half-open probe, want open", stats.State)
+	}
+	if stats.Trips != 2 {
+		t.Fatalf("trips = %d, want 2", stats.Trips)
+	}
+}
+
+func TestCircuitBreakerHalfOpenAdmitsOnlyOneProbe(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+
+	b.RecordFailure() // trips the breaker
+	time.Sleep(20 * time.Millisecond)
+
+	const callers = 10
+	var admitted int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			if err := b.Allow(); err == nil {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if admitted != 1 {
+		t.Errorf("admitted %d concurrent callers once half-open, want exactly 1 probe", admitted)
+	}
+}
+
+func TestCircuitBreakerAllowsCallsWhileClosed(t *testing.T) {
+	b := newCircuitBreaker(5, time.Minute)
+	for i := 0; i < 10; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow() = %v on a fresh breaker, want nil", err)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheInvalidateRequest is the body of POST /api/cache/invalidate. An
+// empty or omitted Messages list clears the whole cache.
+type cacheInvalidateRequest struct {
+	Messages []string `json:"messages"`
+}
+
+// CacheConfig selects and configures the Cache in front of analyzeMessage.
+type CacheConfig struct {
+	Type        string `json:"type"` // "", "none", "memory", "redis"
+	TTL         string `json:"ttl"`
+	MaxEntries  int    `json:"max_entries"`
+	RedisAddr   string `json:"redis_addr"`
+	AdminSecret string `json:"admin_secret"`
+}
+
+// defaultCacheTTL bounds a cache entry's lifetime when CacheConfig.TTL is
+// unset.
+const defaultCacheTTL = 10 * time.Minute
+
+// defaultCacheMaxEntries bounds an in-memory cache's size when
+// CacheConfig.MaxEntries is unset.
+const defaultCacheMaxEntries = 10000
+
+// Cache stores analysisResult values keyed by cacheKey, so repeated
+// moderation calls for identical (model, policies, template, message)
+// tuples can skip the provider round-trip.
+type Cache interface {
+	Get(ctx context.Context, key string) (analysisResult, bool, error)
+	Set(ctx context.Context, key string, result analysisResult, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Clear(ctx context.Context) error
+}
+
+// NewCache constructs the Cache named by cfg.Type. An empty or "none" Type
+// disables caching.
+func NewCache(cfg CacheConfig) (Cache, error) {
+	switch cfg.Type {
+	case "", "none":
+		return nil, nil
+	case "memory":
+		maxEntries := cfg.MaxEntries
+		if maxEntries <= 0 {
+			maxEntries = defaultCacheMaxEntries
+		}
+		return newMemoryCache(maxEntries), nil
+	case "redis":
+		if cfg.RedisAddr == "" {
+			return nil, fmt.Errorf("cache: redis_addr is required for cache type %q", cfg.Type)
+		}
+		return newRedisCache(newRESPClient(cfg.RedisAddr)), nil
+	default:
+		return nil, fmt.Errorf("unknown cache type %q", cfg.Type)
+	}
+}
+
+// cacheKey hashes the inputs that determine a moderation result so that
+// identical requests hit the cache under a fixed-length key, whatever the
+// size of the inputs. Policies are hashed in slice order, so the same
+// policies in a different order give a different key.
+func cacheKey(model string, policies []Policy, promptTemplate, message string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00", model)
+	for _, p := range policies {
+		fmt.Fprintf(h, "%s\x00%s\x00%g\x00%g\x00%s\x00", p.ID, p.Description, p.Severity, p.Threshold, strings.Join(p.Examples, "\x00"))
+	}
+	fmt.Fprintf(h, "%s\x00%s", promptTemplate, message)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CacheStats is a point-in-time snapshot of cache hit/miss counts.
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// cacheCounters tracks hit/miss counts across Cache implementations. It's
+// held by Config rather than by each Cache so the counters survive an
+// admin-triggered Clear.
+type cacheCounters struct {
+	mu     sync.Mutex
+	hits   int64
+	misses int64
+}
+
+func (c *cacheCounters) recordHit() {
+	c.mu.Lock()
+	c.hits++
+	c.mu.Unlock()
+}
+
+func (c *cacheCounters) recordMiss() {
+	c.mu.Lock()
+	c.misses++
+	c.mu.Unlock()
+}
+
+func (c *cacheCounters) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses}
+}
+
+// ---- in-memory LRU + TTL ----
+
+type memoryCacheEntry struct {
+	key       string
+	result    analysisResult
+	expiresAt time.Time
+}
+
+// memoryCache is a fixed-capacity LRU cache with per-entry TTL. Expired
+// entries are evicted lazily on Get.
+type memoryCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front = most recently used
+}
+
+func newMemoryCache(maxEntries int) *memoryCache {
+	return &memoryCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string) (analysisResult, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return analysisResult{}, false, nil
+	}
+
+	entry := el.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return analysisResult{}, false, nil
+	}
+
+	c.order.MoveToFront(el)
+	return entry.result, true, nil
+}
+
+func (c *memoryCache) Set(ctx context.Context, key string, result analysisResult, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*memoryCacheEntry).result = result
+		el.Value.(*memoryCacheEntry).expiresAt = time.Now().Add(ttl)
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	el := c.order.PushFront(&memoryCacheEntry{key: key, result: result, expiresAt: time.Now().Add(ttl)})
+	c.entries[key] = el
+
+	for len(c.entries) > c.maxEntries {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+
+	return nil
+}
+
+func (c *memoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+func (c *memoryCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order = list.New()
+	return nil
+}
+
+// ---- Redis-backed ----
+
+// RedisClient is the minimal surface redisCache needs from a Redis
+// driver. NewCache wires up the stdlib-only respClient by default; a
+// third-party driver (e.g. github.com/redis/go-redis/v9's *redis.Client,
+// wrapped to drop its Cmd return types) can be injected instead via
+// newRedisCache for callers that need pooling or cluster support.
+type RedisClient interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
+
+// redisCache stores cache entries as JSON strings in Redis, with
+// expiration delegated to the Redis TTL rather than tracked locally.
+type redisCache struct {
+	client RedisClient
+}
+
+func newRedisCache(client RedisClient) *redisCache {
+	return &redisCache{client: client}
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) (analysisResult, bool, error) {
+	raw, err := c.client.Get(ctx, key)
+	if err != nil {
+		return analysisResult{}, false, fmt.Errorf("error reading from redis: %w", err)
+	}
+	if raw == "" {
+		return analysisResult{}, false, nil
+	}
+
+	var result analysisResult
+	if err := json.Unmarshal([]byte(raw), &result); err != nil {
+		return analysisResult{}, false, fmt.Errorf("error decoding cached result: %w", err)
+	}
+	return result, true, nil
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, result analysisResult, ttl time.Duration) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("error encoding cached result: %w", err)
+	}
+	if err := c.client.Set(ctx, key, string(raw), ttl); err != nil {
+		return fmt.Errorf("error writing to redis: %w", err)
+	}
+	return nil
+}
+
+func (c *redisCache) Delete(ctx context.Context, key string) error {
+	if err := c.client.Del(ctx, key); err != nil {
+		return fmt.Errorf("error deleting from redis: %w", err)
+	}
+	return nil
+}
+
+func (c *redisCache) Clear(ctx context.Context) error {
+	return fmt.Errorf("redis cache does not support clearing all entries; use per-key Delete or an external FLUSHDB")
+}
+
+// respClient is a minimal RESP (REdis Serialization Protocol) client
+// implementing RedisClient against a single addr with GET/SETEX/DEL. It
+// dials a fresh connection per call rather than pooling, which keeps it
+// simple and is adequate at cache-miss-path volumes; a pooled client can
+// be dropped in later by satisfying the same interface.
+type respClient struct {
+	addr    string
+	dialer  net.Dialer
+	timeout time.Duration
+}
+
+// defaultRedisTimeout bounds a single respClient round-trip, including
+// connection setup.
+const defaultRedisTimeout = 2 * time.Second
+
+func newRESPClient(addr string) *respClient {
+	return &respClient{addr: addr, timeout: defaultRedisTimeout}
+}
+
+func (c *respClient) do(ctx context.Context, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return "", fmt.Errorf("error dialing redis at %s: %w", c.addr, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var req bytes.Buffer
+	fmt.Fprintf(&req, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&req, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		return "", fmt.Errorf("error writing to redis: %w", err)
+	}
+
+	return readRESPReply(bufio.NewReader(conn))
+}
+
+// readRESPReply reads a single RESP reply and returns its string payload.
+// A nil bulk reply (RESP's "not found") is reported as "", nil. Array
+// replies are not supported and are reported as an error.
+func readRESPReply(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("error reading redis reply: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) == 0 {
+		return "", fmt.Errorf("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+': // simple string, e.g. +OK
+		return line[1:], nil
+	case '-': // error
+		return "", fmt.Errorf("redis error: %s", line[1:])
+	case ':': // integer
+		return line[1:], nil
+	case '$': // bulk string
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("error parsing redis bulk length: %w", err)
+		}
+		if n < 0 {
+			return "", nil // nil bulk reply: key not found
+		}
+		buf := make([]byte, n+2) // payload + trailing CRLF
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", fmt.Errorf("error reading redis bulk payload: %w", err)
+		}
+		return string(buf[:n]), nil
+	default:
+		return "", fmt.Errorf("unsupported redis reply type %q", line[0])
+	}
+}
+
+func (c *respClient) Get(ctx context.Context, key string) (string, error) {
+	return c.do(ctx, "GET", key)
+}
+
+func (c *respClient) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	seconds := int64(ttl.Seconds())
+	if seconds <= 0 {
+		seconds = 1
+	}
+	_, err := c.do(ctx, "SETEX", key, strconv.FormatInt(seconds, 10), value)
+	return err
+}
+
+func (c *respClient) Del(ctx context.Context, key string) error {
+	_, err := c.do(ctx, "DEL", key)
+	return err
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheGetSet(t *testing.T) {
+	c := newMemoryCache(10)
+	ctx := context.Background()
+
+	if _, ok, err := c.Get(ctx, "missing"); err != nil || ok {
+		t.Fatalf("Get(missing) = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+
+	want := analysisResult{Content: "hello", IsSafe: true}
+	if err := c.Set(ctx, "k1", want, time.Minute); err != nil {
+		t.Fatalf("Set() error: %v", err)
+	}
+
+	got, ok, err := c.Get(ctx, "k1")
+	if err != nil || !ok {
+		t.Fatalf("Get(k1) = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if got.Content != want.Content {
+		t.Errorf("Content = %q, want %q", got.Content, want.Content)
+	}
+}
+
+func TestMemoryCacheExpiresEntries(t *testing.T) {
+	c := newMemoryCache(10)
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "k1", analysisResult{Content: "hello"}, 10*time.Millisecond); err != nil {
+		t.Fatalf("Set() error: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok, err := c.Get(ctx, "k1"); err != nil || ok {
+		t.Fatalf("Get(k1) after TTL = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newMemoryCache(2)
+	ctx := context.Background()
+
+	c.Set(ctx, "k1", analysisResult{Content: "1"}, time.Minute)
+	c.Set(ctx, "k2", analysisResult{Content: "2"}, time.Minute)
+
+	// Touch k1 so k2 becomes the least recently used entry.
+	if _, ok, _ := c.Get(ctx, "k1"); !ok {
+		t.Fatalf("Get(k1) = false, want true")
+	}
+
+	c.Set(ctx, "k3", analysisResult{Content: "3"}, time.Minute)
+
+	if _, ok, _ := c.Get(ctx, "k2"); ok {
+		t.Errorf("k2 survived eviction, want it evicted as the least recently used entry")
+	}
+	if _, ok, _ := c.Get(ctx, "k1"); !ok {
+		t.Errorf("k1 was evicted, want it retained (recently touched)")
+	}
+	if _, ok, _ := c.Get(ctx, "k3"); !ok {
+		t.Errorf("k3 was evicted, want it retained (just inserted)")
+	}
+}
+
+func TestMemoryCacheDeleteAndClear(t *testing.T) {
+	c := newMemoryCache(10)
+	ctx := context.Background()
+
+	c.Set(ctx, "k1", analysisResult{Content: "1"}, time.Minute)
+	c.Set(ctx, "k2", analysisResult{Content: "2"}, time.Minute)
+
+	if err := c.Delete(ctx, "k1"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if _, ok, _ := c.Get(ctx, "k1"); ok {
+		t.Errorf("k1 present after Delete, want absent")
+	}
+
+	if err := c.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error: %v", err)
+	}
+	if _, ok, _ := c.Get(ctx, "k2"); ok {
+		t.Errorf("k2 present after Clear, want absent")
+	}
+}
+
+func TestNewCache(t *testing.T) {
+	if c, err := NewCache(CacheConfig{Type: ""}); err != nil || c != nil {
+		t.Errorf("NewCache(empty type) = (%v, %v), want (nil, nil)", c, err)
+	}
+	if c, err := NewCache(CacheConfig{Type: "none"}); err != nil || c != nil {
+		t.Errorf("NewCache(none) = (%v, %v), want (nil, nil)", c, err)
+	}
+	if c, err := NewCache(CacheConfig{Type: "memory"}); err != nil || c == nil {
+		t.Errorf("NewCache(memory) = (%v, %v), want (non-nil, nil)", c, err)
+	}
+	if _, err := NewCache(CacheConfig{Type: "redis"}); err == nil {
+		t.Errorf("NewCache(redis) with no redis_addr = nil error, want an error")
+	}
+	if c, err := NewCache(CacheConfig{Type: "redis", RedisAddr: "localhost:6379"}); err != nil || c == nil {
+		t.Errorf("NewCache(redis) with redis_addr = (%v, %v), want (non-nil, nil)", c, err)
+	}
+	if _, err := NewCache(CacheConfig{Type: "bogus"}); err == nil {
+		t.Errorf("NewCache(bogus) = nil error, want an error")
+	}
+}
+
+func TestCacheKeyDistinguishesPolicyFields(t *testing.T) {
+	base := Policy{ID: "p1", Description: "be nice", Severity: 1, Threshold: 0.5, Examples: []string{"ex1"}}
+
+	variants := []Policy{
+		base,
+		{ID: "p1", Description: "be different", Severity: 1, Threshold: 0.5, Examples: []string{"ex1"}},
+		{ID: "p1", Description: "be nice", Severity: 2, Threshold: 0.5, Examples: []string{"ex1"}},
+		{ID: "p1", Description: "be nice", Severity: 1, Threshold: 0.9, Examples: []string{"ex1"}},
+		{ID: "p1", Description: "be nice", Severity: 1, Threshold: 0.5, Examples: []string{"ex2"}},
+	}
+
+	seen := make(map[string]bool)
+	for i, p := range variants {
+		key := cacheKey("model", []Policy{p}, "tmpl", "hi")
+		if seen[key] {
+			t.Errorf("variant %d produced a cache key identical to an earlier variant", i)
+		}
+		seen[key] = true
+	}
+}
+
+func TestCacheKeyStableForIdenticalInputs(t *testing.T) {
+	policies := []Policy{{ID: "p1", Description: "be nice", Severity: 1, Threshold: 0.5, Examples: []string{"ex1"}}}
+	a := cacheKey("model", policies, "tmpl", "hi")
+	b := cacheKey("model", policies, "tmpl", "hi")
+	if a != b {
+		t.Errorf("cacheKey is not stable across identical calls: %q != %q", a, b)
+	}
+}
@@ -0,0 +1,234 @@
+// Package client is a hand-written Go client for the Content Moderation
+// Service described by openapi.yaml (operations postApiAnalyze,
+// getApiHealth, postApiCacheInvalidate). It mirrors the shape an
+// oapi-codegen client would have, but isn't generated: this module has no
+// go.mod/go.sum and the available Go toolchain predates what oapi-codegen
+// requires, so keep it in sync with openapi.yaml by hand.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// AnalyzeRequest, AnalysisResult and friends are the client-side copies
+// of the wire types, duplicated rather than imported so this package has
+// no compile-time dependency on the service binary (mirroring how
+// oapi-codegen generates client and server models independently per
+// package).
+type AnalyzeRequest struct {
+	Messages []string `json:"messages"`
+	Stream   bool     `json:"stream,omitempty"`
+}
+
+type AnalysisResult struct {
+	Content          string             `json:"content"`
+	IsSafe           bool               `json:"is_safe"`
+	PolicyScores     map[string]float64 `json:"policy_scores"`
+	ViolatedPolicies []string           `json:"violated_policies"`
+	Error            string             `json:"error,omitempty"`
+}
+
+type CacheInvalidateRequest struct {
+	Messages []string `json:"messages,omitempty"`
+}
+
+type BreakerStats struct {
+	State string `json:"state"`
+	Trips int64  `json:"trips"`
+}
+
+type EndpointStats struct {
+	Retries int64   `json:"retries"`
+	P50Ms   float64 `json:"p50_ms"`
+	P95Ms   float64 `json:"p95_ms"`
+}
+
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+type HealthResponse struct {
+	Ollama    string                   `json:"ollama"`
+	Breaker   BreakerStats             `json:"breaker"`
+	Endpoints map[string]EndpointStats `json:"endpoints"`
+	Cache     CacheStats               `json:"cache"`
+}
+
+// HTTPRequestDoer is satisfied by *http.Client and by any test double
+// that can execute a built *http.Request.
+type HTTPRequestDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// MiddlewareFunc wraps an http.RoundTripper, the standard way to
+// instrument outgoing requests on an *http.Client's Transport (the same
+// shape used by otelhttp and similar instrumentation libraries).
+type MiddlewareFunc func(http.RoundTripper) http.RoundTripper
+
+// doerRoundTripper adapts an HTTPRequestDoer to http.RoundTripper so
+// WithMiddleware can wrap the configured doer like any other transport.
+type doerRoundTripper struct {
+	doer HTTPRequestDoer
+}
+
+func (t doerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.doer.Do(req)
+}
+
+// RequestEditorFn mutates an outgoing request before it's sent, e.g. to
+// attach auth headers.
+type RequestEditorFn func(ctx context.Context, req *http.Request) error
+
+// ClientOption configures a Client constructed by NewClient.
+type ClientOption func(*Client)
+
+// WithHTTPClient overrides the HTTPRequestDoer used to execute requests;
+// it defaults to http.DefaultClient.
+func WithHTTPClient(doer HTTPRequestDoer) ClientOption {
+	return func(c *Client) { c.client = doer }
+}
+
+// WithRequestEditorFn registers a hook run on every outgoing request.
+func WithRequestEditorFn(fn RequestEditorFn) ClientOption {
+	return func(c *Client) { c.requestEditors = append(c.requestEditors, fn) }
+}
+
+// WithMiddleware wraps the client's transport with mw, outermost first,
+// applying to every request this client sends.
+func WithMiddleware(mw ...MiddlewareFunc) ClientOption {
+	return func(c *Client) { c.middleware = append(c.middleware, mw...) }
+}
+
+// ClientInterface is implemented by Client; callers can substitute a mock
+// satisfying it in tests.
+type ClientInterface interface {
+	AnalyzeWithResponse(ctx context.Context, body AnalyzeRequest, noCache bool) ([]AnalysisResult, error)
+	HealthWithResponse(ctx context.Context) (*HealthResponse, error)
+	CacheInvalidateWithResponse(ctx context.Context, body CacheInvalidateRequest, adminSecret string) error
+}
+
+var _ ClientInterface = (*Client)(nil)
+
+// Client is a typed wrapper around the Content Moderation Service's HTTP
+// API.
+type Client struct {
+	baseURL        string
+	client         HTTPRequestDoer
+	requestEditors []RequestEditorFn
+	middleware     []MiddlewareFunc
+	transport      http.RoundTripper
+}
+
+func NewClient(baseURL string, opts ...ClientOption) *Client {
+	c := &Client{baseURL: baseURL, client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	var rt http.RoundTripper = doerRoundTripper{doer: c.client}
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		rt = c.middleware[i](rt)
+	}
+	c.transport = rt
+	return c
+}
+
+// do runs req through any registered request editors, then through the
+// middleware-wrapped transport.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	for _, edit := range c.requestEditors {
+		if err := edit(req.Context(), req); err != nil {
+			return nil, fmt.Errorf("error editing request: %w", err)
+		}
+	}
+	return c.transport.RoundTrip(req)
+}
+
+func (c *Client) AnalyzeWithResponse(ctx context.Context, body AnalyzeRequest, noCache bool) ([]AnalysisResult, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/analyze", bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("error building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if noCache {
+		req.Header.Set("X-No-Cache", "1")
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error calling /api/analyze: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, errBody)
+	}
+
+	var results []AnalysisResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	return results, nil
+}
+
+func (c *Client) HealthWithResponse(ctx context.Context) (*HealthResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/health", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error building request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error calling /api/health: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, errBody)
+	}
+
+	var health HealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	return &health, nil
+}
+
+func (c *Client) CacheInvalidateWithResponse(ctx context.Context, body CacheInvalidateRequest, adminSecret string) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error encoding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/cache/invalidate", bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("error building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Admin-Secret", adminSecret)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("error calling /api/cache/invalidate: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		errBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, errBody)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// newResilientClient builds an *http.Client tuned for calling out to a
+// single upstream LLM backend: bounded connection pooling with dial,
+// TLS-handshake and response-header deadlines, exponential backoff with
+// jitter on transient failures, and a circuit breaker that trips after
+// consecutive failures so a downed backend stops getting hammered.
+// Retry counts and latency percentiles are recorded per endpoint (request
+// path) in metrics, and breaker state is shared across every request made
+// through the returned client.
+func newResilientClient(breaker *circuitBreaker, metrics *Metrics, maxRetries int) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	}
+
+	return &http.Client{
+		Transport: &resilientTransport{
+			next:       transport,
+			breaker:    breaker,
+			metrics:    metrics,
+			maxRetries: maxRetries,
+		},
+	}
+}
+
+// resilientTransport wraps an http.RoundTripper with retry-with-backoff
+// and circuit breaker behavior. It implements http.RoundTripper so it
+// drops into an *http.Client's Transport field unchanged.
+type resilientTransport struct {
+	next       http.RoundTripper
+	breaker    *circuitBreaker
+	metrics    *Metrics
+	maxRetries int
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := req.URL.Path
+
+	if err := t.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	var resp *http.Response
+	var err error
+
+	for attempt := 0; attempt <= t.maxRetries; attempt++ {
+		if attempt > 0 {
+			t.metrics.RecordRetry(endpoint)
+			select {
+			case <-time.After(backoffWithJitter(attempt)):
+			case <-req.Context().Done():
+				t.breaker.RecordFailure()
+				return nil, req.Context().Err()
+			}
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					t.breaker.RecordFailure()
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+
+		start := time.Now()
+		resp, err = t.next.RoundTrip(req)
+		t.metrics.RecordLatency(endpoint, time.Since(start))
+
+		if !isRetryable(resp, err) {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+
+	if isRetryable(resp, err) {
+		t.breaker.RecordFailure()
+	} else {
+		t.breaker.RecordSuccess()
+	}
+
+	return resp, err
+}
+
+// isRetryable reports whether a response/error pair warrants another
+// attempt: connection-level errors, or 5xx responses from the upstream.
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+// backoffWithJitter returns the delay before retry attempt n (1-indexed):
+// exponential base of 100ms, capped at 2s, with up to 50% random jitter to
+// avoid retry storms against a recovering backend.
+func backoffWithJitter(attempt int) time.Duration {
+	const base = 100 * time.Millisecond
+	const maxBackoff = 2 * time.Second
+
+	backoff := time.Duration(math.Min(float64(maxBackoff), float64(base)*math.Pow(2, float64(attempt-1))))
+	jitter := time.Duration(rand.Int63n(int64(backoff) / 2))
+	return backoff + jitter
+}
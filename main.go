@@ -3,33 +3,62 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"sync"
 	"text/template"
+	"time"
 )
 
 type Config struct {
-	OllamaURL      string      `json:"ollama_url"`
-	Model          string      `json:"model"`
-	PromptTemplate string      `json:"prompt_template"`
-	Policies       []string    `json:"policies"`
-	ResponseFormat interface{} `json:"response_format"`
+	OllamaURL      string         `json:"ollama_url"`
+	Model          string         `json:"model"`
+	PromptTemplate string         `json:"prompt_template"`
+	Policies       []Policy       `json:"policies"`
+	PolicyPacks    []string       `json:"policy_packs"`
+	ResponseFormat interface{}    `json:"response_format"`
+	Provider       ProviderConfig `json:"provider"`
+	MaxConcurrency int            `json:"max_concurrency"`
+	RequestTimeout string         `json:"request_timeout"`
+	Cache          CacheConfig    `json:"cache"`
+
+	provider       Provider
+	requestTimeout time.Duration
+	metrics        *Metrics
+	breaker        *circuitBreaker
+	httpClient     *http.Client
+	cache          Cache
+	cacheTTL       time.Duration
+	cacheCounters  *cacheCounters
 }
 
 type analyzeRequest struct {
 	Messages []string `json:"messages"`
+	Stream   bool     `json:"stream"`
 }
 
 type analysisResult struct {
-	Content          string   `json:"content"`
-	IsSafe           bool     `json:"is_safe"`
-	ViolatedPolicies []string `json:"violated_policies"`
+	Content          string             `json:"content"`
+	IsSafe           bool               `json:"is_safe"`
+	PolicyScores     map[string]float64 `json:"policy_scores"`
+	ViolatedPolicies []string           `json:"violated_policies"`
+	Error            string             `json:"error,omitempty"`
 }
 
+// defaultMaxConcurrency bounds the worker pool size when Config.MaxConcurrency
+// is unset.
+const defaultMaxConcurrency = 4
+
+// defaultRequestTimeout bounds a single analyzeMessage call when
+// Config.RequestTimeout is unset.
+const defaultRequestTimeout = 30 * time.Second
+
 type ollamaGenerateRequest struct {
 	Model  string      `json:"model"`
 	Prompt string      `json:"prompt"`
@@ -37,9 +66,67 @@ type ollamaGenerateRequest struct {
 	Format interface{} `json:"format"`
 }
 
+// moderationResult is the raw per-policy scoring the model returns, before
+// aggregateScores turns it into an overall safety verdict.
 type moderationResult struct {
-	Safe             bool     `json:"safe"`
-	ViolatedPolicies []string `json:"violated_policies"`
+	Scores map[string]float64 `json:"scores"`
+}
+
+// parsePartialScores incrementally decodes the "scores" object out of an
+// in-progress moderationResult stream, returning whatever complete
+// policy->score pairs have arrived so far. Unlike json.Unmarshal, which
+// only succeeds once buf holds a complete JSON value, this tolerates a
+// still-open object by walking it with a json.Decoder and stopping at the
+// first incomplete token, so streamAnalyzeMessage can surface real partial
+// scores instead of nothing until the stream ends.
+func parsePartialScores(buf []byte) map[string]float64 {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+
+	// Advance past `{ "scores" : {` to reach the scores object body.
+	if tok, err := dec.Token(); err != nil {
+		return nil
+	} else if d, ok := tok.(json.Delim); !ok || d.String() != "{" {
+		return nil
+	}
+	if tok, err := dec.Token(); err != nil {
+		return nil
+	} else if s, ok := tok.(string); !ok || s != "scores" {
+		return nil
+	}
+	if tok, err := dec.Token(); err != nil {
+		return nil
+	} else if d, ok := tok.(json.Delim); !ok || d.String() != "{" {
+		return nil
+	}
+
+	scores := make(map[string]float64)
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return scores
+		}
+		key, ok := keyTok.(string)
+		if !ok {
+			return scores
+		}
+
+		valTok, err := dec.Token()
+		if err != nil {
+			return scores
+		}
+		val, ok := valTok.(float64)
+		if !ok {
+			return scores
+		}
+		// A number that runs to the end of buf may still be growing
+		// (e.g. "0.5" of a later "0.55"), so it isn't complete yet.
+		if dec.InputOffset() >= int64(len(buf)) {
+			return scores
+		}
+
+		scores[key] = val
+	}
+	return scores
 }
 
 func loadConfig(path string) (*Config, error) {
@@ -53,14 +140,68 @@ func loadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("error parsing config file: %w", err)
 	}
 
-	if cfg.OllamaURL == "" || cfg.Model == "" || cfg.PromptTemplate == "" {
+	if cfg.Model == "" || cfg.PromptTemplate == "" {
+		return nil, fmt.Errorf("missing required fields in config")
+	}
+
+	if cfg.Provider.BaseURL == "" {
+		cfg.Provider.BaseURL = cfg.OllamaURL
+	}
+	if cfg.Provider.BaseURL == "" {
 		return nil, fmt.Errorf("missing required fields in config")
 	}
 
+	for _, path := range cfg.PolicyPacks {
+		packPolicies, err := loadPolicyPack(path)
+		if err != nil {
+			return nil, fmt.Errorf("error loading policy pack %q: %w", path, err)
+		}
+		cfg.Policies = append(cfg.Policies, packPolicies...)
+	}
+
+	cfg.metrics = NewMetrics()
+	cfg.breaker = newCircuitBreaker(5, 30*time.Second)
+	cfg.httpClient = newResilientClient(cfg.breaker, cfg.metrics, 3)
+
+	provider, err := NewProvider(cfg.Provider, cfg.ResponseFormat, cfg.httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring provider: %w", err)
+	}
+	cfg.provider = provider
+
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = defaultMaxConcurrency
+	}
+
+	cfg.requestTimeout = defaultRequestTimeout
+	if cfg.RequestTimeout != "" {
+		d, err := time.ParseDuration(cfg.RequestTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing request_timeout: %w", err)
+		}
+		cfg.requestTimeout = d
+	}
+
+	cache, err := NewCache(cfg.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring cache: %w", err)
+	}
+	cfg.cache = cache
+	cfg.cacheCounters = &cacheCounters{}
+
+	cfg.cacheTTL = defaultCacheTTL
+	if cfg.Cache.TTL != "" {
+		d, err := time.ParseDuration(cfg.Cache.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing cache.ttl: %w", err)
+		}
+		cfg.cacheTTL = d
+	}
+
 	return &cfg, nil
 }
 
-func generatePrompt(message string, policies []string, promptTemplate string) (string, error) {
+func generatePrompt(message string, policies []Policy, promptTemplate string) (string, error) {
 	tmpl, err := template.New("prompt").Funcs(template.FuncMap{
 		"inc": func(i int) int { return i + 1 },
 	}).Parse(promptTemplate)
@@ -70,7 +211,7 @@ func generatePrompt(message string, policies []string, promptTemplate string) (s
 
 	data := struct {
 		Message  string
-		Policies []string
+		Policies []Policy
 	}{
 		Message:  message,
 		Policies: policies,
@@ -84,88 +225,194 @@ func generatePrompt(message string, policies []string, promptTemplate string) (s
 	return buf.String(), nil
 }
 
-func handleAnalyze(cfg *Config) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
+// strictServer implements StrictServerInterface, the typed contract
+// defined in server.go for the operations in openapi.yaml. Request bodies
+// arrive already bound and validated by the strict-handler wrapper;
+// handlers here just do the work and return a typed response object.
+type strictServer struct {
+	cfg *Config
+}
 
-		var req analyzeRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			return
-		}
+func (s *strictServer) PostApiAnalyze(ctx context.Context, request PostApiAnalyzeRequestObject) (PostApiAnalyzeResponseObject, error) {
+	req := request.Body
 
-		if len(req.Messages) == 0 {
-			http.Error(w, "Messages array cannot be empty", http.StatusBadRequest)
-			return
+	if req.Stream {
+		w, _ := ctx.Value(responseWriterCtxKey{}).(http.ResponseWriter)
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			return PostApiAnalyze400Response{Message: "Streaming not supported"}, nil
 		}
 
-		results := make([]analysisResult, 0, len(req.Messages))
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
 		for _, message := range req.Messages {
-			result, err := analyzeMessage(r.Context(), message, cfg)
+			if err := streamAnalyzeMessage(ctx, w, flusher, message, s.cfg); err != nil {
+				log.Printf("Error streaming message '%s': %v", message, err)
+			}
+		}
+		return PostApiAnalyze200SSEResponse{}, nil
+	}
+
+	noCache := request.Params.XNoCache != nil && *request.Params.XNoCache == "1"
+	results := analyzeMessagesConcurrently(ctx, req.Messages, s.cfg, noCache)
+	return PostApiAnalyze200JSONResponse(results), nil
+}
+
+// analyzeMessagesConcurrently fans the given messages out across a worker
+// pool bounded by cfg.MaxConcurrency, preserving the input order in the
+// returned slice. Each message gets its own cfg.requestTimeout deadline,
+// and the whole pool is cancelled if ctx (the request context) is done.
+// A failure analyzing one message never drops it from the response; it
+// is instead surfaced via analysisResult.Error.
+func analyzeMessagesConcurrently(ctx context.Context, messages []string, cfg *Config, noCache bool) []analysisResult {
+	results := make([]analysisResult, len(messages))
+
+	sem := make(chan struct{}, cfg.MaxConcurrency)
+	var wg sync.WaitGroup
+
+	for i, message := range messages {
+		wg.Add(1)
+		go func(i int, message string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = analysisResult{Content: message, Error: ctx.Err().Error()}
+				return
+			}
+
+			msgCtx, cancel := context.WithTimeout(ctx, cfg.requestTimeout)
+			defer cancel()
+
+			result, err := analyzeMessage(msgCtx, message, cfg, noCache)
 			if err != nil {
 				log.Printf("Error analyzing message '%s': %v", message, err)
-				continue
+				results[i] = analysisResult{Content: message, Error: err.Error()}
+				return
 			}
-			results = append(results, result)
-		}
+			results[i] = result
+		}(i, message)
+	}
+
+	wg.Wait()
+	return results
+}
+
+func analyzeMessage(ctx context.Context, message string, cfg *Config, noCache bool) (analysisResult, error) {
+	key := cacheKey(cfg.Model, cfg.Policies, cfg.PromptTemplate, message)
+	cacheEnabled := cfg.cache != nil && !noCache
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(results); err != nil {
-			log.Printf("Error encoding response: %v", err)
+	if cacheEnabled {
+		if cached, ok, err := cfg.cache.Get(ctx, key); err != nil {
+			log.Printf("Error reading cache: %v", err)
+		} else if ok {
+			cfg.cacheCounters.recordHit()
+			return cached, nil
 		}
+		cfg.cacheCounters.recordMiss()
 	}
-}
 
-func analyzeMessage(ctx context.Context, message string, cfg *Config) (analysisResult, error) {
 	prompt, err := generatePrompt(message, cfg.Policies, cfg.PromptTemplate)
 	if err != nil {
 		return analysisResult{}, fmt.Errorf("error generating prompt: %w", err)
 	}
 
-	ollamaReq := ollamaGenerateRequest{
-		Model:  cfg.Model,
-		Prompt: prompt,
-		Stream: false,
-		Format: cfg.ResponseFormat,
+	response, err := cfg.provider.Chat(ctx, cfg.Model, prompt)
+	if err != nil {
+		return analysisResult{}, fmt.Errorf("error calling provider: %w", err)
 	}
 
-	reqBody, _ := json.Marshal(ollamaReq)
-	url := fmt.Sprintf("%s/api/generate", cfg.OllamaURL)
-	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
-	req.Header.Set("Content-Type", "application/json")
+	var modResult moderationResult
+	if err := json.Unmarshal([]byte(response), &modResult); err != nil {
+		return analysisResult{}, fmt.Errorf("error parsing moderation result: %w", err)
+	}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		return analysisResult{}, fmt.Errorf("API request failed: %w", err)
+	isSafe, violated := aggregateScores(modResult.Scores, cfg.Policies)
+	result := analysisResult{
+		Content:          message,
+		IsSafe:           isSafe,
+		PolicyScores:     modResult.Scores,
+		ViolatedPolicies: violated,
 	}
-	defer resp.Body.Close()
 
-	var ollamaResp struct {
-		Response string `json:"response"`
+	if cacheEnabled {
+		if err := cfg.cache.Set(ctx, key, result, cfg.cacheTTL); err != nil {
+			log.Printf("Error writing cache: %v", err)
+		}
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		return analysisResult{}, fmt.Errorf("error decoding response: %w", err)
+
+	return result, nil
+}
+
+// streamAnalyzeMessage analyzes message via the provider's streaming API,
+// emitting one Server-Sent Event per partial moderationResult parse as
+// tokens accumulate, followed by a final event carrying the full result.
+func streamAnalyzeMessage(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, message string, cfg *Config) error {
+	prompt, err := generatePrompt(message, cfg.Policies, cfg.PromptTemplate)
+	if err != nil {
+		return fmt.Errorf("error generating prompt: %w", err)
+	}
+
+	tokens, errs := cfg.provider.ChatStream(ctx, cfg.Model, prompt)
+
+	var buf bytes.Buffer
+	for tok := range tokens {
+		buf.WriteString(tok.Content)
+
+		if scores := parsePartialScores(buf.Bytes()); len(scores) > 0 {
+			isSafe, violated := aggregateScores(scores, cfg.Policies)
+			writeSSEEvent(w, flusher, "partial", analysisResult{
+				Content:          message,
+				IsSafe:           isSafe,
+				PolicyScores:     scores,
+				ViolatedPolicies: violated,
+			})
+		}
+
+		if tok.Done {
+			break
+		}
+	}
+
+	if err := <-errs; err != nil {
+		writeSSEEvent(w, flusher, "error", map[string]string{"error": err.Error()})
+		return fmt.Errorf("error streaming from provider: %w", err)
 	}
 
 	var modResult moderationResult
-	if err := json.Unmarshal([]byte(ollamaResp.Response), &modResult); err != nil {
-		return analysisResult{}, fmt.Errorf("error parsing moderation result: %w", err)
+	if err := json.Unmarshal(buf.Bytes(), &modResult); err != nil {
+		writeSSEEvent(w, flusher, "error", map[string]string{"error": err.Error()})
+		return fmt.Errorf("error parsing moderation result: %w", err)
 	}
 
-	return analysisResult{
+	isSafe, violated := aggregateScores(modResult.Scores, cfg.Policies)
+	writeSSEEvent(w, flusher, "done", analysisResult{
 		Content:          message,
-		IsSafe:           modResult.Safe,
-		ViolatedPolicies: modResult.ViolatedPolicies,
-	}, nil
+		IsSafe:           isSafe,
+		PolicyScores:     modResult.Scores,
+		ViolatedPolicies: violated,
+	})
+	return nil
+}
+
+func writeSSEEvent(w http.ResponseWriter, flusher http.Flusher, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Error encoding SSE event: %v", err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	flusher.Flush()
 }
 
 func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		if r.Method == http.MethodOptions {
@@ -176,33 +423,79 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func handleOllamaHealth(ollamaURL string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		url := fmt.Sprintf("%s/api/version", ollamaURL)
-		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("creating version request: %v", err), http.StatusServiceUnavailable)
-			return
-		}
+type healthResponse struct {
+	Ollama    string                   `json:"ollama"`
+	Breaker   breakerStats             `json:"breaker"`
+	Endpoints map[string]EndpointStats `json:"endpoints"`
+	Cache     CacheStats               `json:"cache"`
+}
 
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("connecting to Ollama: %v", err), http.StatusServiceUnavailable)
-			return
+func (s *strictServer) GetApiHealth(ctx context.Context, _ GetApiHealthRequestObject) (GetApiHealthResponseObject, error) {
+	cfg := s.cfg
+	resp := healthResponse{
+		Breaker:   cfg.breaker.Stats(),
+		Endpoints: cfg.metrics.Snapshot(),
+		Cache:     cfg.cacheCounters.Stats(),
+	}
+
+	url := fmt.Sprintf("%s/api/version", cfg.Provider.BaseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		resp.Ollama = fmt.Sprintf("error: %v", err)
+	} else if versionResp, err := cfg.httpClient.Do(req); err != nil {
+		resp.Ollama = fmt.Sprintf("error: %v", err)
+	} else {
+		defer versionResp.Body.Close()
+		body, _ := io.ReadAll(versionResp.Body)
+		if versionResp.StatusCode != http.StatusOK {
+			resp.Ollama = fmt.Sprintf("unexpected status %d: %s", versionResp.StatusCode, body)
+		} else {
+			resp.Ollama = string(body)
 		}
-		defer resp.Body.Close()
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			http.Error(w, fmt.Sprintf("unexpected version status code %d: %s", resp.StatusCode, body), http.StatusBadGateway)
-			return
+	return GetApiHealth200JSONResponse(resp), nil
+}
+
+// PostApiCacheInvalidate evicts cache entries. With a "messages" body it
+// evicts just those entries; with no body (or an empty list) it clears the
+// whole cache. Gated by a shared secret in X-Admin-Secret so it can't be
+// hit by arbitrary callers of the public API.
+func (s *strictServer) PostApiCacheInvalidate(ctx context.Context, request PostApiCacheInvalidateRequestObject) (PostApiCacheInvalidateResponseObject, error) {
+	cfg := s.cfg
+
+	if cfg.Cache.AdminSecret == "" || !constantTimeEquals(request.AdminSecret, cfg.Cache.AdminSecret) {
+		return PostApiCacheInvalidate401Response{}, nil
+	}
+	if cfg.cache == nil {
+		return PostApiCacheInvalidate400Response{Message: "Caching is disabled"}, nil
+	}
+
+	messages := request.Body.Messages
+	if len(messages) == 0 {
+		if err := cfg.cache.Clear(ctx); err != nil {
+			return PostApiCacheInvalidate400Response{Message: fmt.Sprintf("error clearing cache: %v", err)}, nil
 		}
+		return PostApiCacheInvalidate204Response{}, nil
+	}
 
-		w.Write([]byte("ollama: "))
-		if _, err := io.Copy(w, resp.Body); err != nil {
-			log.Printf("Error copying response: %v", err)
+	for _, message := range messages {
+		key := cacheKey(cfg.Model, cfg.Policies, cfg.PromptTemplate, message)
+		if err := cfg.cache.Delete(ctx, key); err != nil {
+			return PostApiCacheInvalidate400Response{Message: fmt.Sprintf("error invalidating cache entry: %v", err)}, nil
 		}
 	}
+	return PostApiCacheInvalidate204Response{}, nil
+}
+
+// constantTimeEquals reports whether got equals want without leaking
+// either string's content or length through comparison timing, as
+// expected of an admin-secret check. Hashing first sidesteps
+// subtle.ConstantTimeCompare's own length short-circuit.
+func constantTimeEquals(got, want string) bool {
+	gotHash := sha256.Sum256([]byte(got))
+	wantHash := sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(gotHash[:], wantHash[:]) == 1
 }
 
 func main() {
@@ -212,13 +505,31 @@ func main() {
 	}
 
 	mux := http.NewServeMux()
+	server := NewStrictHandler(&strictServer{cfg: cfg}, nil)
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Content moderation service is running"))
 	})
 
-	mux.HandleFunc("/api/health", corsMiddleware(handleOllamaHealth(cfg.OllamaURL)))
-	mux.HandleFunc("/api/analyze", corsMiddleware(handleAnalyze(cfg)))
+	mux.HandleFunc("/api/health", corsMiddleware(server.GetApiHealth))
+	mux.HandleFunc("/api/analyze", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Method == http.MethodGet {
+			server.GetApiAnalyze(w, r)
+			return
+		}
+		server.PostApiAnalyze(w, r)
+	}))
+	mux.HandleFunc("/api/cache/invalidate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		server.PostApiCacheInvalidate(w, r)
+	})
 
 	port := os.Getenv("PORT")
 	if port == "" {
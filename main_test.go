@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a Provider test double whose Chat behavior is supplied
+// per test; ChatStream is unused by these tests.
+type fakeProvider struct {
+	chat func(ctx context.Context, model, prompt string) (string, error)
+}
+
+func (p *fakeProvider) Chat(ctx context.Context, model, prompt string) (string, error) {
+	return p.chat(ctx, model, prompt)
+}
+
+func (p *fakeProvider) ChatStream(ctx context.Context, model, prompt string) (<-chan ChatToken, <-chan error) {
+	tokens := make(chan ChatToken)
+	errs := make(chan error, 1)
+	close(tokens)
+	close(errs)
+	return tokens, errs
+}
+
+func testConfig(maxConcurrency int, provider Provider) *Config {
+	return &Config{
+		Model:          "test-model",
+		PromptTemplate: "{{.Message}}",
+		Policies:       []Policy{{ID: "p1", Severity: 1, Threshold: 0.5}},
+		MaxConcurrency: maxConcurrency,
+		requestTimeout: time.Second,
+		provider:       provider,
+	}
+}
+
+func TestAnalyzeMessagesConcurrentlyPreservesOrder(t *testing.T) {
+	messages := []string{"a", "b", "c", "d", "e"}
+
+	provider := &fakeProvider{
+		chat: func(ctx context.Context, model, prompt string) (string, error) {
+			// Reply slower for earlier messages so completion order is the
+			// reverse of submission order; the result slice must still
+			// line up with the input slice by index.
+			switch prompt {
+			case "a":
+				time.Sleep(30 * time.Millisecond)
+			case "b":
+				time.Sleep(20 * time.Millisecond)
+			case "c":
+				time.Sleep(10 * time.Millisecond)
+			}
+			return fmt.Sprintf(`{"scores":{"p1":0}}`), nil
+		},
+	}
+
+	cfg := testConfig(len(messages), provider)
+	results := analyzeMessagesConcurrently(context.Background(), messages, cfg, true)
+
+	if len(results) != len(messages) {
+		t.Fatalf("got %d results, want %d", len(results), len(messages))
+	}
+	for i, m := range messages {
+		if results[i].Content != m {
+			t.Errorf("results[%d].Content = %q, want %q", i, results[i].Content, m)
+		}
+	}
+}
+
+func TestAnalyzeMessagesConcurrentlyBoundsConcurrency(t *testing.T) {
+	const maxConcurrency = 2
+	messages := []string{"a", "b", "c", "d", "e", "f"}
+
+	var mu sync.Mutex
+	var current, max int
+	provider := &fakeProvider{
+		chat: func(ctx context.Context, model, prompt string) (string, error) {
+			mu.Lock()
+			current++
+			if current > max {
+				max = current
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			current--
+			mu.Unlock()
+
+			return `{"scores":{"p1":0}}`, nil
+		},
+	}
+
+	cfg := testConfig(maxConcurrency, provider)
+	analyzeMessagesConcurrently(context.Background(), messages, cfg, true)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if max > maxConcurrency {
+		t.Errorf("observed %d concurrent calls, want at most %d", max, maxConcurrency)
+	}
+}
+
+func TestAnalyzeMessagesConcurrentlySurfacesPerMessageError(t *testing.T) {
+	messages := []string{"good", "bad"}
+
+	provider := &fakeProvider{
+		chat: func(ctx context.Context, model, prompt string) (string, error) {
+			if prompt == "bad" {
+				return "", fmt.Errorf("provider exploded")
+			}
+			return `{"scores":{"p1":0}}`, nil
+		},
+	}
+
+	cfg := testConfig(len(messages), provider)
+	results := analyzeMessagesConcurrently(context.Background(), messages, cfg, true)
+
+	if results[0].Error != "" {
+		t.Errorf("results[0].Error = %q, want empty", results[0].Error)
+	}
+	if results[1].Error == "" {
+		t.Errorf("results[1].Error is empty, want an error for the failing message")
+	}
+}
+
+func TestAnalyzeMessagesConcurrentlyCancellation(t *testing.T) {
+	messages := []string{"a", "b", "c"}
+
+	provider := &fakeProvider{
+		chat: func(ctx context.Context, model, prompt string) (string, error) {
+			t.Fatalf("provider.Chat called for %q after context was already cancelled", prompt)
+			return "", nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// MaxConcurrency 0 makes the worker pool's semaphore send permanently
+	// blocked, so every goroutine must take the ctx.Done() branch.
+	cfg := testConfig(0, provider)
+	results := analyzeMessagesConcurrently(ctx, messages, cfg, true)
+
+	for i, r := range results {
+		if r.Error == "" {
+			t.Errorf("results[%d].Error is empty, want %v", i, context.Canceled)
+		}
+	}
+}
+
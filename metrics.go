@@ -0,0 +1,100 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// endpointMetrics accumulates retry counts and latency samples for a
+// single upstream endpoint (keyed by request path).
+type endpointMetrics struct {
+	retries   int64
+	latencies []time.Duration
+}
+
+// Metrics tracks retry counts and request latencies per endpoint, read by
+// the health handler to report p50/p95 figures alongside circuit breaker
+// state. Safe for concurrent use.
+type Metrics struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointMetrics
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{endpoints: make(map[string]*endpointMetrics)}
+}
+
+func (m *Metrics) endpoint(name string) *endpointMetrics {
+	e, ok := m.endpoints[name]
+	if !ok {
+		e = &endpointMetrics{}
+		m.endpoints[name] = e
+	}
+	return e
+}
+
+// RecordRetry increments the retry count for endpoint.
+func (m *Metrics) RecordRetry(endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endpoint(endpoint).retries++
+}
+
+// RecordLatency appends a latency sample for endpoint. Only the most
+// recent maxLatencySamples are kept so memory stays bounded under
+// sustained traffic.
+func (m *Metrics) RecordLatency(endpoint string, d time.Duration) {
+	const maxLatencySamples = 1000
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.endpoint(endpoint)
+	e.latencies = append(e.latencies, d)
+	if len(e.latencies) > maxLatencySamples {
+		e.latencies = e.latencies[len(e.latencies)-maxLatencySamples:]
+	}
+}
+
+// EndpointStats is a point-in-time snapshot of one endpoint's metrics.
+type EndpointStats struct {
+	Retries int64   `json:"retries"`
+	P50Ms   float64 `json:"p50_ms"`
+	P95Ms   float64 `json:"p95_ms"`
+}
+
+// Snapshot returns a copy of the current per-endpoint stats.
+func (m *Metrics) Snapshot() map[string]EndpointStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make(map[string]EndpointStats, len(m.endpoints))
+	for name, e := range m.endpoints {
+		out[name] = EndpointStats{
+			Retries: e.retries,
+			P50Ms:   percentile(e.latencies, 0.50).Seconds() * 1000,
+			P95Ms:   percentile(e.latencies, 0.95).Seconds() * 1000,
+		}
+	}
+	return out
+}
+
+// percentile returns the p-th percentile (0-1) of samples, rounded to the
+// nearest millisecond. It copies samples before sorting so the caller's
+// slice is left untouched.
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(p * float64(len(sorted)))
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx].Round(time.Millisecond)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Policy describes a single moderation rule the model is asked to score.
+// Severity weights how much a violation of this policy contributes to the
+// overall safety verdict; Threshold is the per-policy score (0.0-1.0)
+// at or above which the policy is considered violated.
+type Policy struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	Severity    float64  `json:"severity"`
+	Threshold   float64  `json:"threshold"`
+	Examples    []string `json:"examples"`
+}
+
+// aggregateSafetyThreshold is the severity-weighted violation fraction
+// at or above which a message is considered unsafe overall.
+const aggregateSafetyThreshold = 0.5
+
+// aggregateScores turns a per-policy score map into an overall safety
+// verdict: a policy is violated when its score meets its own Threshold,
+// and the message is unsafe when the severity-weighted fraction of
+// violated policies reaches aggregateSafetyThreshold.
+func aggregateScores(scores map[string]float64, policies []Policy) (isSafe bool, violated []string) {
+	var totalSeverity, violatedSeverity float64
+
+	for _, p := range policies {
+		totalSeverity += p.Severity
+		if scores[p.ID] >= p.Threshold {
+			violated = append(violated, p.ID)
+			violatedSeverity += p.Severity
+		}
+	}
+
+	if totalSeverity == 0 {
+		return len(violated) == 0, violated
+	}
+	return violatedSeverity/totalSeverity < aggregateSafetyThreshold, violated
+}
+
+// loadPolicyPack reads a policy pack: a JSON file containing an array of
+// Policy, letting teams compose a base pack with industry-specific packs
+// without editing the main config.
+func loadPolicyPack(path string) ([]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading policy pack file: %w", err)
+	}
+
+	var policies []Policy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("error parsing policy pack file: %w", err)
+	}
+	return policies, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAggregateScoresSeverityWeighting(t *testing.T) {
+	policies := []Policy{
+		{ID: "minor", Severity: 1, Threshold: 0.5},
+		{ID: "major", Severity: 4, Threshold: 0.5},
+	}
+
+	// Only the low-severity policy is violated: violated fraction is
+	// 1/5 = 0.2, below aggregateSafetyThreshold, so still safe.
+	isSafe, violated := aggregateScores(map[string]float64{"minor": 0.9, "major": 0.1}, policies)
+	if !isSafe {
+		t.Errorf("isSafe = false, want true when only the low-severity policy is violated")
+	}
+	if len(violated) != 1 || violated[0] != "minor" {
+		t.Errorf("violated = %v, want [minor]", violated)
+	}
+
+	// The high-severity policy is violated: violated fraction is
+	// 4/5 = 0.8, at or above aggregateSafetyThreshold, so unsafe.
+	isSafe, violated = aggregateScores(map[string]float64{"minor": 0.1, "major": 0.9}, policies)
+	if isSafe {
+		t.Errorf("isSafe = true, want false when the high-severity policy is violated")
+	}
+	if len(violated) != 1 || violated[0] != "major" {
+		t.Errorf("violated = %v, want [major]", violated)
+	}
+}
+
+func TestAggregateScoresThresholdIsPerPolicy(t *testing.T) {
+	policies := []Policy{{ID: "p1", Severity: 1, Threshold: 0.8}}
+
+	isSafe, violated := aggregateScores(map[string]float64{"p1": 0.79}, policies)
+	if !isSafe || len(violated) != 0 {
+		t.Errorf("score just below threshold: isSafe=%v violated=%v, want true, []", isSafe, violated)
+	}
+
+	isSafe, violated = aggregateScores(map[string]float64{"p1": 0.8}, policies)
+	if isSafe || len(violated) != 1 {
+		t.Errorf("score at threshold: isSafe=%v violated=%v, want false, [p1]", isSafe, violated)
+	}
+}
+
+func TestAggregateScoresNoPoliciesIsSafe(t *testing.T) {
+	isSafe, violated := aggregateScores(map[string]float64{"p1": 1.0}, nil)
+	if !isSafe || violated != nil {
+		t.Errorf("isSafe=%v violated=%v, want true, nil when there are no policies", isSafe, violated)
+	}
+}
+
+func TestLoadPolicyPack(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pack.json")
+	const data = `[{"id":"p1","description":"d","severity":1,"threshold":0.5,"examples":["e1"]}]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	policies, err := loadPolicyPack(path)
+	if err != nil {
+		t.Fatalf("loadPolicyPack error: %v", err)
+	}
+	if len(policies) != 1 || policies[0].ID != "p1" {
+		t.Fatalf("policies = %+v, want a single policy with ID p1", policies)
+	}
+}
+
+func TestLoadPolicyPackMissingFile(t *testing.T) {
+	if _, err := loadPolicyPack(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("loadPolicyPack(missing file) = nil error, want an error")
+	}
+}
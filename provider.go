@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ChatToken is a single unit of incremental output from a streaming
+// provider call. Done is set on the final token, at which point Content
+// may be empty.
+type ChatToken struct {
+	Content string
+	Done    bool
+}
+
+// Provider is the common contract implemented by each backend LLM API.
+// It lets analyzeMessage issue a moderation call without knowing whether
+// it's talking to Ollama, an OpenAI-compatible API, or Anthropic's API.
+type Provider interface {
+	// Chat performs a single non-streaming completion and returns the
+	// full response text.
+	Chat(ctx context.Context, model, prompt string) (string, error)
+
+	// ChatStream performs a streaming completion. Tokens are delivered on
+	// the returned channel as they arrive; the error channel carries at
+	// most one error and is closed alongside the token channel.
+	ChatStream(ctx context.Context, model, prompt string) (<-chan ChatToken, <-chan error)
+}
+
+// ProviderConfig selects and configures a Provider backend.
+type ProviderConfig struct {
+	Type    string `json:"type"` // "ollama", "openai", "anthropic"
+	BaseURL string `json:"base_url"`
+	APIKey  string `json:"api_key"`
+}
+
+// NewProvider constructs the Provider named by cfg.Type. An empty Type
+// defaults to "ollama" so existing configs keep working unchanged.
+// responseFormat is passed through to backends that support constraining
+// their output to a schema (currently only Ollama's "format" field); it is
+// ignored by backends that don't.
+func NewProvider(cfg ProviderConfig, responseFormat interface{}, client *http.Client) (Provider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	switch cfg.Type {
+	case "", "ollama":
+		return &ollamaProvider{baseURL: cfg.BaseURL, client: client, format: responseFormat}, nil
+	case "openai":
+		return &openAIProvider{baseURL: cfg.BaseURL, apiKey: cfg.APIKey, client: client}, nil
+	case "anthropic":
+		return &anthropicProvider{baseURL: cfg.BaseURL, apiKey: cfg.APIKey, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", cfg.Type)
+	}
+}
+
+// ---- Ollama ----
+
+type ollamaProvider struct {
+	baseURL string
+	client  *http.Client
+	format  interface{}
+}
+
+func (p *ollamaProvider) Chat(ctx context.Context, model, prompt string) (string, error) {
+	body, _ := json.Marshal(ollamaGenerateRequest{Model: model, Prompt: prompt, Stream: false, Format: p.format})
+	resp, err := p.post(ctx, "/api/generate", body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Response string `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("error decoding response: %w", err)
+	}
+	return out.Response, nil
+}
+
+func (p *ollamaProvider) ChatStream(ctx context.Context, model, prompt string) (<-chan ChatToken, <-chan error) {
+	tokens := make(chan ChatToken)
+	errs := make(chan error, 1)
+
+	body, _ := json.Marshal(ollamaGenerateRequest{Model: model, Prompt: prompt, Stream: true, Format: p.format})
+	resp, err := p.post(ctx, "/api/generate", body)
+	if err != nil {
+		errs <- err
+		close(tokens)
+		close(errs)
+		return tokens, errs
+	}
+
+	go func() {
+		defer close(tokens)
+		defer close(errs)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var chunk struct {
+				Response string `json:"response"`
+				Done     bool   `json:"done"`
+			}
+			line := scanner.Bytes()
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				errs <- fmt.Errorf("error decoding stream chunk: %w", err)
+				return
+			}
+			tokens <- ChatToken{Content: chunk.Response, Done: chunk.Done}
+			if chunk.Done {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			errs <- fmt.Errorf("error reading stream: %w", err)
+		}
+	}()
+
+	return tokens, errs
+}
+
+func (p *ollamaProvider) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	url := fmt.Sprintf("%s%s", p.baseURL, path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("API request failed: unexpected status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// ---- OpenAI-compatible ----
+
+type openAIProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+type openAIChatRequest struct {
+	Model    string `json:"model"`
+	Stream   bool   `json:"stream"`
+	Messages []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+}
+
+func (p *openAIProvider) newRequest(ctx context.Context, model, prompt string, stream bool) (*http.Request, error) {
+	reqBody := openAIChatRequest{Model: model, Stream: stream}
+	reqBody.Messages = append(reqBody.Messages, struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}{Role: "user", Content: prompt})
+
+	body, _ := json.Marshal(reqBody)
+	url := fmt.Sprintf("%s/chat/completions", p.baseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	return req, nil
+}
+
+func (p *openAIProvider) Chat(ctx context.Context, model, prompt string) (string, error) {
+	req, err := p.newRequest(ctx, model, prompt, false)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API request failed: unexpected status %d", resp.StatusCode)
+	}
+
+	var out struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("error decoding response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("empty choices in response")
+	}
+	return out.Choices[0].Message.Content, nil
+}
+
+func (p *openAIProvider) ChatStream(ctx context.Context, model, prompt string) (<-chan ChatToken, <-chan error) {
+	tokens := make(chan ChatToken)
+	errs := make(chan error, 1)
+
+	req, err := p.newRequest(ctx, model, prompt, true)
+	if err != nil {
+		errs <- err
+		close(tokens)
+		close(errs)
+		return tokens, errs
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		errs <- fmt.Errorf("API request failed: %w", err)
+		close(tokens)
+		close(errs)
+		return tokens, errs
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		errs <- fmt.Errorf("API request failed: unexpected status %d", resp.StatusCode)
+		close(tokens)
+		close(errs)
+		return tokens, errs
+	}
+
+	go func() {
+		defer close(tokens)
+		defer close(errs)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				tokens <- ChatToken{Done: true}
+				return
+			}
+
+			var chunk struct {
+				Choices []struct {
+					Delta struct {
+						Content string `json:"content"`
+					} `json:"delta"`
+					FinishReason *string `json:"finish_reason"`
+				} `json:"choices"`
+			}
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				errs <- fmt.Errorf("error decoding stream chunk: %w", err)
+				return
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			done := chunk.Choices[0].FinishReason != nil
+			tokens <- ChatToken{Content: chunk.Choices[0].Delta.Content, Done: done}
+			if done {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			errs <- fmt.Errorf("error reading stream: %w", err)
+		}
+	}()
+
+	return tokens, errs
+}
+
+// ---- Anthropic ----
+
+type anthropicProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+type anthropicMessagesRequest struct {
+	Model     string `json:"model"`
+	Stream    bool   `json:"stream"`
+	MaxTokens int    `json:"max_tokens"`
+	Messages  []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+}
+
+func (p *anthropicProvider) newRequest(ctx context.Context, model, prompt string, stream bool) (*http.Request, error) {
+	reqBody := anthropicMessagesRequest{Model: model, Stream: stream, MaxTokens: 1024}
+	reqBody.Messages = append(reqBody.Messages, struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}{Role: "user", Content: prompt})
+
+	body, _ := json.Marshal(reqBody)
+	url := fmt.Sprintf("%s/v1/messages", p.baseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	return req, nil
+}
+
+func (p *anthropicProvider) Chat(ctx context.Context, model, prompt string) (string, error) {
+	req, err := p.newRequest(ctx, model, prompt, false)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API request failed: unexpected status %d", resp.StatusCode)
+	}
+
+	var out struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("error decoding response: %w", err)
+	}
+	if len(out.Content) == 0 {
+		return "", fmt.Errorf("empty content in response")
+	}
+	return out.Content[0].Text, nil
+}
+
+func (p *anthropicProvider) ChatStream(ctx context.Context, model, prompt string) (<-chan ChatToken, <-chan error) {
+	tokens := make(chan ChatToken)
+	errs := make(chan error, 1)
+
+	req, err := p.newRequest(ctx, model, prompt, true)
+	if err != nil {
+		errs <- err
+		close(tokens)
+		close(errs)
+		return tokens, errs
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		errs <- fmt.Errorf("API request failed: %w", err)
+		close(tokens)
+		close(errs)
+		return tokens, errs
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		errs <- fmt.Errorf("API request failed: unexpected status %d", resp.StatusCode)
+		close(tokens)
+		close(errs)
+		return tokens, errs
+	}
+
+	go func() {
+		defer close(tokens)
+		defer close(errs)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+			var event struct {
+				Type  string `json:"type"`
+				Delta struct {
+					Text string `json:"text"`
+				} `json:"delta"`
+			}
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				errs <- fmt.Errorf("error decoding stream chunk: %w", err)
+				return
+			}
+
+			switch event.Type {
+			case "content_block_delta":
+				tokens <- ChatToken{Content: event.Delta.Text}
+			case "message_stop":
+				tokens <- ChatToken{Done: true}
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			errs <- fmt.Errorf("error reading stream: %w", err)
+		}
+	}()
+
+	return tokens, errs
+}
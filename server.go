@@ -0,0 +1,232 @@
+// Package main implements the strict-server pattern popularized by
+// oapi-codegen (ServerInterface/StrictServerInterface, typed request and
+// response objects, a NewStrictHandler adapter) against the operations
+// described in openapi.yaml. It's hand-written rather than generated: this
+// module has no go.mod/go.sum, and oapi-codegen requires a newer Go
+// toolchain than is available here, so there's nothing to regenerate it
+// with. Keep it and openapi.yaml in sync by hand when the API changes.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// AnalyzeRequest, AnalysisResult, CacheInvalidateRequest and HealthResponse
+// alias the service's own wire types so the strict-server signatures below
+// read the same as they would against oapi-codegen's generated models.
+type AnalyzeRequest = analyzeRequest
+type AnalysisResult = analysisResult
+type CacheInvalidateRequest = cacheInvalidateRequest
+type HealthResponse = healthResponse
+
+// ServerInterface is the plain, net/http-flavored server contract.
+// NewStrictHandler adapts a StrictServerInterface implementation to it;
+// strict handlers that stream reach the raw ResponseWriter through
+// responseWriterCtxKey.
+type ServerInterface interface {
+	PostApiAnalyze(w http.ResponseWriter, r *http.Request)
+	GetApiAnalyze(w http.ResponseWriter, r *http.Request)
+	GetApiHealth(w http.ResponseWriter, r *http.Request)
+	PostApiCacheInvalidate(w http.ResponseWriter, r *http.Request)
+}
+
+// StrictServerInterface receives already-decoded request objects and
+// returns typed response objects, so individual handlers don't each need
+// their own json.NewDecoder/json.NewEncoder calls.
+type StrictServerInterface interface {
+	PostApiAnalyze(ctx context.Context, request PostApiAnalyzeRequestObject) (PostApiAnalyzeResponseObject, error)
+	GetApiHealth(ctx context.Context, request GetApiHealthRequestObject) (GetApiHealthResponseObject, error)
+	PostApiCacheInvalidate(ctx context.Context, request PostApiCacheInvalidateRequestObject) (PostApiCacheInvalidateResponseObject, error)
+}
+
+// responseWriterCtxKey carries the raw http.ResponseWriter into strict
+// handler implementations that need it for streaming (text/event-stream)
+// responses, which don't fit the typed-response-object model.
+type responseWriterCtxKey struct{}
+
+// ---- /api/analyze ----
+
+type PostApiAnalyzeParams struct {
+	XNoCache *string
+}
+
+type PostApiAnalyzeRequestObject struct {
+	Params PostApiAnalyzeParams
+	Body   *AnalyzeRequest
+}
+
+type PostApiAnalyzeResponseObject interface {
+	VisitPostApiAnalyzeResponse(w http.ResponseWriter) error
+}
+
+type PostApiAnalyze200JSONResponse []AnalysisResult
+
+func (r PostApiAnalyze200JSONResponse) VisitPostApiAnalyzeResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(r)
+}
+
+type PostApiAnalyze400Response struct {
+	Message string
+}
+
+func (r PostApiAnalyze400Response) VisitPostApiAnalyzeResponse(w http.ResponseWriter) error {
+	http.Error(w, r.Message, http.StatusBadRequest)
+	return nil
+}
+
+// PostApiAnalyze200SSEResponse is returned once a streaming response has
+// already been written directly to the ResponseWriter (see
+// responseWriterCtxKey); Visit is a no-op.
+type PostApiAnalyze200SSEResponse struct{}
+
+func (PostApiAnalyze200SSEResponse) VisitPostApiAnalyzeResponse(w http.ResponseWriter) error {
+	return nil
+}
+
+// ---- /api/health ----
+
+type GetApiHealthRequestObject struct{}
+
+type GetApiHealthResponseObject interface {
+	VisitGetApiHealthResponse(w http.ResponseWriter) error
+}
+
+type GetApiHealth200JSONResponse HealthResponse
+
+func (r GetApiHealth200JSONResponse) VisitGetApiHealthResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(r)
+}
+
+// ---- /api/cache/invalidate ----
+
+type PostApiCacheInvalidateRequestObject struct {
+	Body        *CacheInvalidateRequest
+	AdminSecret string
+}
+
+type PostApiCacheInvalidateResponseObject interface {
+	VisitPostApiCacheInvalidateResponse(w http.ResponseWriter) error
+}
+
+type PostApiCacheInvalidate204Response struct{}
+
+func (PostApiCacheInvalidate204Response) VisitPostApiCacheInvalidateResponse(w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type PostApiCacheInvalidate400Response struct{ Message string }
+
+func (r PostApiCacheInvalidate400Response) VisitPostApiCacheInvalidateResponse(w http.ResponseWriter) error {
+	http.Error(w, r.Message, http.StatusBadRequest)
+	return nil
+}
+
+type PostApiCacheInvalidate401Response struct{}
+
+func (PostApiCacheInvalidate401Response) VisitPostApiCacheInvalidateResponse(w http.ResponseWriter) error {
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	return nil
+}
+
+// ---- strict -> plain adapter ----
+
+// MiddlewareFunc uses the net/http decorator signature, so it composes
+// directly with net/http, chi, and gorilla/mux routers without adaptation.
+type MiddlewareFunc func(http.Handler) http.Handler
+
+type strictHandler struct {
+	ssi         StrictServerInterface
+	middlewares []MiddlewareFunc
+}
+
+// NewStrictHandler adapts a StrictServerInterface implementation to the
+// plain ServerInterface that main.go's mux registers, running it through
+// middlewares (outermost first).
+func NewStrictHandler(ssi StrictServerInterface, middlewares []MiddlewareFunc) ServerInterface {
+	return &strictHandler{ssi: ssi, middlewares: middlewares}
+}
+
+func (h *strictHandler) wrap(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	var wrapped http.Handler = handlerFunc
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		wrapped = h.middlewares[i](wrapped)
+	}
+	return wrapped.ServeHTTP
+}
+
+func (h *strictHandler) PostApiAnalyze(w http.ResponseWriter, r *http.Request) {
+	h.wrap(func(w http.ResponseWriter, r *http.Request) {
+		body, err := bindAnalyzeRequest(r)
+		if err != nil {
+			_ = PostApiAnalyze400Response{Message: "Invalid request body"}.VisitPostApiAnalyzeResponse(w)
+			return
+		}
+		if len(body.Messages) == 0 {
+			_ = PostApiAnalyze400Response{Message: "Messages array cannot be empty"}.VisitPostApiAnalyzeResponse(w)
+			return
+		}
+
+		var params PostApiAnalyzeParams
+		if v := r.Header.Get("X-No-Cache"); v != "" {
+			params.XNoCache = &v
+		}
+
+		ctx := context.WithValue(r.Context(), responseWriterCtxKey{}, w)
+		resp, err := h.ssi.PostApiAnalyze(ctx, PostApiAnalyzeRequestObject{Params: params, Body: &body})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := resp.VisitPostApiAnalyzeResponse(w); err != nil {
+			log.Printf("Error writing /api/analyze response: %v", err)
+		}
+	})(w, r)
+}
+
+func (h *strictHandler) GetApiAnalyze(w http.ResponseWriter, r *http.Request) {
+	h.PostApiAnalyze(w, r)
+}
+
+func (h *strictHandler) GetApiHealth(w http.ResponseWriter, r *http.Request) {
+	h.wrap(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := h.ssi.GetApiHealth(r.Context(), GetApiHealthRequestObject{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := resp.VisitGetApiHealthResponse(w); err != nil {
+			log.Printf("Error writing /api/health response: %v", err)
+		}
+	})(w, r)
+}
+
+func (h *strictHandler) PostApiCacheInvalidate(w http.ResponseWriter, r *http.Request) {
+	h.wrap(func(w http.ResponseWriter, r *http.Request) {
+		var body CacheInvalidateRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				_ = PostApiCacheInvalidate400Response{Message: "Invalid request body"}.VisitPostApiCacheInvalidateResponse(w)
+				return
+			}
+		}
+
+		req := PostApiCacheInvalidateRequestObject{
+			Body:        &body,
+			AdminSecret: r.Header.Get("X-Admin-Secret"),
+		}
+		resp, err := h.ssi.PostApiCacheInvalidate(r.Context(), req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := resp.VisitPostApiCacheInvalidateResponse(w); err != nil {
+			log.Printf("Error writing /api/cache/invalidate response: %v", err)
+		}
+	})(w, r)
+}